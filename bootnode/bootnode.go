@@ -0,0 +1,40 @@
+// Package bootnode wires up a noise.Node whose sole purpose is to answer
+// S/Kademlia PING and LOOKUP requests and keep its routing table populated,
+// without originating application traffic or joining higher-level protocols.
+//
+// It mirrors the role the Ethereum "bootnode" plays for devp2p: a
+// lightweight, always-on discovery anchor that operators can hand out to
+// new peers as a seed address.
+package bootnode
+
+import (
+	"github.com/perlin-network/noise"
+	"github.com/perlin-network/noise/skademlia"
+	"github.com/pkg/errors"
+)
+
+// Bootnode wraps a noise.Node running skademlia in bootstrap-only mode.
+type Bootnode struct {
+	Node     *noise.Node
+	Protocol *skademlia.Protocol
+}
+
+// New registers a bootstrap-only S/Kademlia protocol onto node using keys
+// and externalAddress, and returns the resulting Bootnode.
+func New(node *noise.Node, keys *skademlia.Keypair, externalAddress string) (*Bootnode, error) {
+	if node == nil {
+		return nil, errors.New("bootnode: node must not be nil")
+	}
+
+	protocol := skademlia.New(keys, externalAddress).WithBootstrapOnly(true)
+
+	node.OnPeerInit(protocol.Handshake)
+
+	return &Bootnode{Node: node, Protocol: protocol}, nil
+}
+
+// URI returns the address and identity of the bootnode in the form
+// operators can hand out as a seed, e.g. "host:port deadbeef...".
+func (n *Bootnode) URI() string {
+	return n.Protocol.Self().String()
+}
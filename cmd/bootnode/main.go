@@ -0,0 +1,80 @@
+// Command bootnode runs a standalone S/Kademlia discovery node: it answers
+// PING and LOOKUP requests and populates its routing table, but never
+// originates application traffic. Point other nodes' bootstrap seeds at the
+// address it prints on startup.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/perlin-network/noise"
+	"github.com/perlin-network/noise/bootnode"
+	"github.com/perlin-network/noise/skademlia"
+)
+
+func main() {
+	var (
+		addr       = flag.String("addr", ":3000", "listening address of the bootnode")
+		nodeKey    = flag.String("nodekey", "", "path to a file containing a hex-encoded private key")
+		nodeKeyHex = flag.String("nodekeyhex", "", "hex-encoded private key")
+		genKey     = flag.String("genkey", "", "generate a keypair solving the S/Kademlia puzzle and write it to this file, then exit")
+	)
+
+	flag.Parse()
+
+	if *genKey != "" {
+		keys := skademlia.NewKeypair(skademlia.DefaultC1, skademlia.DefaultC2)
+
+		if err := ioutil.WriteFile(*genKey, []byte(keys.PrivateKeyHex()), 0600); err != nil {
+			log.Fatalf("bootnode: failed to write keypair to %s: %v", *genKey, err)
+		}
+
+		return
+	}
+
+	keys, err := loadKeypair(*nodeKey, *nodeKeyHex)
+
+	if err != nil {
+		log.Fatalf("bootnode: %v", err)
+	}
+
+	node, err := noise.Listen(*addr)
+
+	if err != nil {
+		log.Fatalf("bootnode: failed to listen on %s: %v", *addr, err)
+	}
+
+	b, err := bootnode.New(node, keys, *addr)
+
+	if err != nil {
+		log.Fatalf("bootnode: failed to start: %v", err)
+	}
+
+	fmt.Printf("Bootnode is listening for connections. Hand out this node URI to seed other peers:\n%s\n", b.URI())
+
+	select {}
+}
+
+func loadKeypair(path, hex string) (*skademlia.Keypair, error) {
+	if hex != "" {
+		return skademlia.LoadKeypairHex(hex)
+	}
+
+	if path != "" {
+		raw, err := ioutil.ReadFile(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return skademlia.LoadKeypairHex(string(raw))
+	}
+
+	fmt.Fprintln(os.Stderr, "bootnode: no -nodekey or -nodekeyhex given; generating an ephemeral keypair")
+
+	return skademlia.NewKeypair(skademlia.DefaultC1, skademlia.DefaultC2), nil
+}
@@ -0,0 +1,28 @@
+// Package logger defines the small, leveled logging interface shared by
+// network and skademlia, so callers can wire noise into their own logging
+// pipeline instead of having it write to stdout.
+package logger
+
+// Logger is implemented by anything capable of emitting leveled,
+// structured log lines. kv is a flat list of alternating keys and values,
+// e.g. Debug("dialing peer", "addr", addr, "id", id).
+type Logger interface {
+	Trace(msg string, kv ...interface{})
+	Debug(msg string, kv ...interface{})
+	Info(msg string, kv ...interface{})
+	Warn(msg string, kv ...interface{})
+	Error(msg string, kv ...interface{})
+}
+
+// Nop discards everything logged to it. It's the default logger for both
+// network's package-level helpers and skademlia.Protocol until
+// SetLogger/WithLogger is called.
+var Nop Logger = nop{}
+
+type nop struct{}
+
+func (nop) Trace(string, ...interface{}) {}
+func (nop) Debug(string, ...interface{}) {}
+func (nop) Info(string, ...interface{})  {}
+func (nop) Warn(string, ...interface{})  {}
+func (nop) Error(string, ...interface{}) {}
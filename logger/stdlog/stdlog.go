@@ -0,0 +1,43 @@
+// Package stdlog adapts the standard library's log.Logger to logger.Logger.
+package stdlog
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/perlin-network/noise/logger"
+)
+
+// New adapts l to logger.Logger. Every level is emitted at the same
+// verbosity since *log.Logger has no concept of levels; the level name is
+// prefixed onto the message instead.
+func New(l *log.Logger) logger.Logger {
+	return &adapter{l}
+}
+
+type adapter struct {
+	l *log.Logger
+}
+
+func (a *adapter) Trace(msg string, kv ...interface{}) { a.log("TRACE", msg, kv...) }
+func (a *adapter) Debug(msg string, kv ...interface{}) { a.log("DEBUG", msg, kv...) }
+func (a *adapter) Info(msg string, kv ...interface{})  { a.log("INFO", msg, kv...) }
+func (a *adapter) Warn(msg string, kv ...interface{})  { a.log("WARN", msg, kv...) }
+func (a *adapter) Error(msg string, kv ...interface{}) { a.log("ERROR", msg, kv...) }
+
+func (a *adapter) log(level, msg string, kv ...interface{}) {
+	a.l.Println(format(level, msg, kv...))
+}
+
+func format(level, msg string, kv ...interface{}) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "[%s] %s", level, msg)
+
+	for i := 0; i+1 < len(kv); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", kv[i], kv[i+1])
+	}
+
+	return b.String()
+}
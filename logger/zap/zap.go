@@ -0,0 +1,24 @@
+// Package zap adapts a *zap.SugaredLogger to logger.Logger.
+package zap
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/perlin-network/noise/logger"
+)
+
+// New adapts l to logger.Logger. zap has no Trace level, so Trace is
+// emitted at Debug.
+func New(l *zap.SugaredLogger) logger.Logger {
+	return &adapter{l}
+}
+
+type adapter struct {
+	l *zap.SugaredLogger
+}
+
+func (a *adapter) Trace(msg string, kv ...interface{}) { a.l.Debugw(msg, kv...) }
+func (a *adapter) Debug(msg string, kv ...interface{}) { a.l.Debugw(msg, kv...) }
+func (a *adapter) Info(msg string, kv ...interface{})  { a.l.Infow(msg, kv...) }
+func (a *adapter) Warn(msg string, kv ...interface{})  { a.l.Warnw(msg, kv...) }
+func (a *adapter) Error(msg string, kv ...interface{}) { a.l.Errorw(msg, kv...) }
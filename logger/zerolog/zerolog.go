@@ -0,0 +1,37 @@
+// Package zerolog adapts a zerolog.Logger to logger.Logger.
+package zerolog
+
+import (
+	"github.com/rs/zerolog"
+
+	"github.com/perlin-network/noise/logger"
+)
+
+// New adapts l to logger.Logger.
+func New(l zerolog.Logger) logger.Logger {
+	return &adapter{l}
+}
+
+type adapter struct {
+	l zerolog.Logger
+}
+
+func (a *adapter) Trace(msg string, kv ...interface{}) { a.event(a.l.Trace(), msg, kv...) }
+func (a *adapter) Debug(msg string, kv ...interface{}) { a.event(a.l.Debug(), msg, kv...) }
+func (a *adapter) Info(msg string, kv ...interface{})  { a.event(a.l.Info(), msg, kv...) }
+func (a *adapter) Warn(msg string, kv ...interface{})  { a.event(a.l.Warn(), msg, kv...) }
+func (a *adapter) Error(msg string, kv ...interface{}) { a.event(a.l.Error(), msg, kv...) }
+
+func (a *adapter) event(e *zerolog.Event, msg string, kv ...interface{}) {
+	for i := 0; i+1 < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+
+		if !ok {
+			continue
+		}
+
+		e = e.Interface(key, kv[i+1])
+	}
+
+	e.Msg(msg)
+}
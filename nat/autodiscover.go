@@ -0,0 +1,78 @@
+package nat
+
+import (
+	"net"
+	"time"
+)
+
+// startAutoDiscovery probes for a UPnP gateway first, falling back to
+// NAT-PMP against the default gateway, and finally to a no-op interface
+// that reports no NAT traversal is available.
+func startAutoDiscovery() Interface {
+	if u := UPnP(); probe(u) {
+		return u
+	}
+
+	if gateway := defaultGateway(); gateway != nil {
+		if p := PMP(gateway); probe(p) {
+			return p
+		}
+	}
+
+	return &none{}
+}
+
+// probe checks whether m can actually reach a gateway.
+func probe(m Interface) bool {
+	_, err := m.ExternalIP()
+	return err == nil
+}
+
+// defaultGateway makes a best-effort guess at the LAN's default gateway by
+// assuming it sits at the .1 address of this host's preferred outbound
+// interface. It returns nil if no suitable interface can be found.
+func defaultGateway() net.IP {
+	ip, err := preferredOutboundIP()
+
+	if err != nil {
+		return nil
+	}
+
+	return net.IPv4(ip[0], ip[1], ip[2], 1)
+}
+
+// preferredOutboundIP returns the IPv4 address of the interface this host
+// would use to reach the public internet, without sending any traffic.
+func preferredOutboundIP() (net.IP, error) {
+	conn, err := net.Dial("udp", "8.8.8.8:80")
+
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	local, ok := conn.LocalAddr().(*net.UDPAddr)
+
+	if !ok || local.IP.To4() == nil {
+		return nil, errNoNAT
+	}
+
+	return local.IP.To4(), nil
+}
+
+// none implements Interface but performs no NAT traversal whatsoever.
+type none struct{}
+
+func (n *none) String() string { return "none" }
+
+func (n *none) ExternalIP() (net.IP, error) {
+	return nil, errNoNAT
+}
+
+func (n *none) AddMapping(protocol string, external, internal int, lease time.Duration) (int, error) {
+	return 0, errNoNAT
+}
+
+func (n *none) DeleteMapping(protocol string, external, internal int) error {
+	return errNoNAT
+}
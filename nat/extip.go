@@ -0,0 +1,26 @@
+package nat
+
+import (
+	"net"
+	"time"
+)
+
+// extIP reports a fixed, operator-supplied external IP and performs no
+// actual port mapping.
+type extIP net.IP
+
+func (e extIP) String() string {
+	return net.IP(e).String()
+}
+
+func (e extIP) ExternalIP() (net.IP, error) {
+	return net.IP(e), nil
+}
+
+func (e extIP) AddMapping(protocol string, external, internal int, lease time.Duration) (int, error) {
+	return external, nil
+}
+
+func (e extIP) DeleteMapping(protocol string, external, internal int) error {
+	return nil
+}
@@ -0,0 +1,104 @@
+// Package nat discovers this host's externally reachable address and, where
+// possible, opens a port mapping for it so that noise.Node and skademlia.New
+// don't have to be handed a pre-configured external address. A wrong
+// externalAddress otherwise breaks S/Kademlia's handshake reachability check
+// (ctx.Peer().Addr().String() != id.address), so getting it right
+// automatically saves operators from hand-configuring ports.
+package nat
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// mappingLease is how long a port mapping is requested for before it needs
+// renewing. Renewal happens well before the lease expires.
+const (
+	mappingLease  = 20 * time.Minute
+	renewInterval = mappingLease / 2
+)
+
+var errNoNAT = errors.New("nat: no NAT traversal mechanism available")
+
+// Interface is implemented by each concrete discovery mechanism (UPnP,
+// NAT-PMP, a fixed external IP, or "do nothing").
+type Interface interface {
+	// ExternalIP returns the host's externally reachable IP address.
+	ExternalIP() (net.IP, error)
+
+	// AddMapping requests that external port maps to internal port for
+	// protocol ("tcp" or "udp"), held for lease. It returns the external
+	// port actually granted, which may differ from the requested one.
+	AddMapping(protocol string, external, internal int, lease time.Duration) (int, error)
+
+	// DeleteMapping tears down a previously requested mapping.
+	DeleteMapping(protocol string, external, internal int) error
+
+	String() string
+}
+
+// UPnP discovers a gateway speaking UPnP IGD.
+func UPnP() Interface {
+	return &upnp{}
+}
+
+// PMP discovers a gateway speaking NAT-PMP at the given address.
+func PMP(gateway net.IP) Interface {
+	return &pmp{gateway: gateway}
+}
+
+// ExtIP reports ip as the external address and performs no port mapping.
+// Useful when the operator already knows their public IP, e.g. behind a
+// cloud load balancer.
+func ExtIP(ip net.IP) Interface {
+	return extIP(ip)
+}
+
+// Any tries UPnP first, then NAT-PMP against the default gateway, and
+// finally falls back to reporting no NAT traversal is available.
+func Any() Interface {
+	return startAutoDiscovery()
+}
+
+// Map resolves m's external address for the TCP listening port, requests a
+// port mapping, and returns the "host:port" to hand to skademlia.New as the
+// node's externalAddress. It launches a background goroutine that renews
+// the mapping until stop is closed. Callers should arrange for stop to be
+// closed (e.g. on noise.Node shutdown) so the mapping is torn down.
+func Map(m Interface, protocol string, port int, stop <-chan struct{}) (string, error) {
+	ip, err := m.ExternalIP()
+
+	if err != nil {
+		return "", errors.Wrap(err, "nat: failed to discover external ip")
+	}
+
+	external, err := m.AddMapping(protocol, port, port, mappingLease)
+
+	if err != nil {
+		return "", errors.Wrap(err, "nat: failed to add port mapping")
+	}
+
+	go renew(m, protocol, external, port, stop)
+
+	return net.JoinHostPort(ip.String(), strconv.Itoa(external)), nil
+}
+
+func renew(m Interface, protocol string, external, internal int, stop <-chan struct{}) {
+	ticker := time.NewTicker(renewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			_ = m.DeleteMapping(protocol, external, internal)
+			return
+		case <-ticker.C:
+			if _, err := m.AddMapping(protocol, external, internal, mappingLease); err != nil {
+				continue
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package nat
+
+import (
+	"encoding/binary"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NAT-PMP (RFC 6886) opcodes and the port it's served on.
+const (
+	pmpPort = 5351
+
+	pmpOpExternalAddress = 0
+	pmpOpMapUDP          = 1
+	pmpOpMapTCP          = 2
+
+	pmpResultSuccess = 0
+
+	pmpRetries     = 3
+	pmpInitialWait = 250 * time.Millisecond
+)
+
+// pmp discovers a gateway speaking NAT-PMP.
+type pmp struct {
+	gateway net.IP
+}
+
+func (p *pmp) String() string {
+	return "NAT-PMP(" + p.gateway.String() + ")"
+}
+
+func (p *pmp) ExternalIP() (net.IP, error) {
+	resp, err := p.call([]byte{0, pmpOpExternalAddress}, 12)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return net.IPv4(resp[8], resp[9], resp[10], resp[11]), nil
+}
+
+func (p *pmp) AddMapping(protocol string, external, internal int, lease time.Duration) (int, error) {
+	op := byte(pmpOpMapUDP)
+
+	if protocol == "tcp" {
+		op = pmpOpMapTCP
+	}
+
+	req := make([]byte, 12)
+	req[1] = op
+	binary.BigEndian.PutUint16(req[4:6], uint16(internal))
+	binary.BigEndian.PutUint16(req[6:8], uint16(external))
+	binary.BigEndian.PutUint32(req[8:12], uint32(lease.Seconds()))
+
+	resp, err := p.call(req, 16)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return int(binary.BigEndian.Uint16(resp[10:12])), nil
+}
+
+func (p *pmp) DeleteMapping(protocol string, external, internal int) error {
+	// A lease of zero deletes the mapping, per RFC 6886 §3.4.
+	_, err := p.AddMapping(protocol, external, internal, 0)
+
+	return err
+}
+
+// call sends req to the gateway's NAT-PMP port and returns a response of
+// respLen bytes, retrying with exponential backoff since NAT-PMP runs
+// unacknowledged over UDP.
+func (p *pmp) call(req []byte, respLen int) ([]byte, error) {
+	conn, err := net.Dial("udp4", net.JoinHostPort(p.gateway.String(), strconv.Itoa(pmpPort)))
+
+	if err != nil {
+		return nil, errors.Wrap(err, "nat: failed to dial NAT-PMP gateway")
+	}
+	defer conn.Close()
+
+	resp := make([]byte, respLen)
+	wait := pmpInitialWait
+
+	for attempt := 0; attempt < pmpRetries; attempt++ {
+		if err := conn.SetDeadline(time.Now().Add(wait)); err != nil {
+			return nil, errors.Wrap(err, "nat: failed to set NAT-PMP deadline")
+		}
+
+		if _, err := conn.Write(req); err != nil {
+			return nil, errors.Wrap(err, "nat: failed to send NAT-PMP request")
+		}
+
+		n, err := conn.Read(resp)
+		wait *= 2
+
+		if err != nil {
+			continue
+		}
+
+		if n < respLen || resp[1] != req[1]+128 {
+			continue
+		}
+
+		if result := binary.BigEndian.Uint16(resp[2:4]); result != pmpResultSuccess {
+			return nil, errors.Errorf("nat: NAT-PMP gateway %s returned result code %d", p.gateway, result)
+		}
+
+		return resp, nil
+	}
+
+	return nil, errors.Errorf("nat: NAT-PMP gateway %s did not respond", p.gateway)
+}
@@ -0,0 +1,169 @@
+package nat
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// gatewayTimeout bounds every HTTP call this file makes to a gateway. A
+// flaky consumer router may accept the TCP connection and then never
+// respond, and these calls run synchronously out of nat.Map during node
+// startup — without a timeout, a stuck gateway would hang startup forever
+// and nat.Any()'s UPnP->NAT-PMP->none fallback would never get a chance to
+// run.
+const gatewayTimeout = 5 * time.Second
+
+var httpClient = &http.Client{Timeout: gatewayTimeout}
+
+// wanConnectionServices are the UPnP IGD service types capable of mapping
+// ports on the WAN side of a gateway.
+var wanConnectionServices = map[string]bool{
+	"urn:schemas-upnp-org:service:WANIPConnection:1":  true,
+	"urn:schemas-upnp-org:service:WANPPPConnection:1": true,
+}
+
+type igdRoot struct {
+	Device igdDevice `xml:"device"`
+}
+
+type igdDevice struct {
+	DeviceList  []igdDevice  `xml:"deviceList>device"`
+	ServiceList []igdService `xml:"serviceList>service"`
+}
+
+type igdService struct {
+	ServiceType string `xml:"serviceType"`
+	ControlURL  string `xml:"controlURL"`
+}
+
+// fetchControlURL downloads and parses the device description advertised at
+// loc, returning the control URL and service type of its WAN connection
+// service.
+func fetchControlURL(loc string) (controlURL, service string, err error) {
+	resp, err := httpClient.Get(loc)
+
+	if err != nil {
+		return "", "", errors.Wrap(err, "nat: failed to fetch device description")
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil {
+		return "", "", errors.Wrap(err, "nat: failed to read device description")
+	}
+
+	var root igdRoot
+
+	if err := xml.Unmarshal(body, &root); err != nil {
+		return "", "", errors.Wrap(err, "nat: failed to parse device description")
+	}
+
+	svc, ok := findWANConnectionService(root.Device)
+
+	if !ok {
+		return "", "", errors.New("nat: gateway has no WANIPConnection/WANPPPConnection service")
+	}
+
+	base, err := url.Parse(loc)
+
+	if err != nil {
+		return "", "", errors.Wrap(err, "nat: failed to parse device description url")
+	}
+
+	control, err := base.Parse(svc.ControlURL)
+
+	if err != nil {
+		return "", "", errors.Wrap(err, "nat: failed to resolve control url")
+	}
+
+	return control.String(), svc.ServiceType, nil
+}
+
+// findWANConnectionService walks dev's service list, recursing into child
+// devices, looking for a WANIPConnection or WANPPPConnection service.
+func findWANConnectionService(dev igdDevice) (igdService, bool) {
+	for _, svc := range dev.ServiceList {
+		if wanConnectionServices[svc.ServiceType] {
+			return svc, true
+		}
+	}
+
+	for _, child := range dev.DeviceList {
+		if svc, ok := findWANConnectionService(child); ok {
+			return svc, true
+		}
+	}
+
+	return igdService{}, false
+}
+
+// soapCall issues a SOAP action against a UPnP control URL and returns the
+// raw response body.
+func soapCall(controlURL, service, action, args string) ([]byte, error) {
+	body := fmt.Sprintf(
+		`<?xml version="1.0"?>`+
+			`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">`+
+			`<s:Body><u:%s xmlns:u="%s">%s</u:%s></s:Body></s:Envelope>`,
+		action, service, args, action)
+
+	req, err := http.NewRequest("POST", controlURL, bytes.NewBufferString(body))
+
+	if err != nil {
+		return nil, errors.Wrap(err, "nat: failed to build soap request")
+	}
+
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", fmt.Sprintf(`"%s#%s"`, service, action))
+
+	resp, err := httpClient.Do(req)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "nat: soap call %s failed", action)
+	}
+	defer resp.Body.Close()
+
+	out, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "nat: failed to read soap response")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("nat: soap call %s returned status %s: %s", action, resp.Status, out)
+	}
+
+	return out, nil
+}
+
+// extractTag pulls the text content of <tag>...</tag> out of a SOAP
+// response body, which is all the single-value responses UPnP IGD actions
+// return require.
+func extractTag(body []byte, tag string) string {
+	s := string(body)
+
+	open, shut := "<"+tag+">", "</"+tag+">"
+
+	start := strings.Index(s, open)
+
+	if start == -1 {
+		return ""
+	}
+
+	start += len(open)
+	end := strings.Index(s[start:], shut)
+
+	if end == -1 {
+		return ""
+	}
+
+	return s[start : start+end]
+}
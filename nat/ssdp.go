@@ -0,0 +1,75 @@
+package nat
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const ssdpAddr = "239.255.255.250:1900"
+
+// ssdpSearch sends an SSDP M-SEARCH for searchTarget over UDP multicast and
+// returns the LOCATION URL advertised by the first gateway that responds
+// within timeout.
+func ssdpSearch(searchTarget string, timeout time.Duration) (string, error) {
+	conn, err := net.ListenPacket("udp4", ":0")
+
+	if err != nil {
+		return "", errors.Wrap(err, "nat: failed to open ssdp socket")
+	}
+	defer conn.Close()
+
+	addr, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+
+	if err != nil {
+		return "", errors.Wrap(err, "nat: failed to resolve ssdp multicast address")
+	}
+
+	req := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: " + ssdpAddr + "\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 2\r\n" +
+		"ST: " + searchTarget + "\r\n\r\n"
+
+	if _, err := conn.WriteTo([]byte(req), addr); err != nil {
+		return "", errors.Wrap(err, "nat: failed to send ssdp discovery request")
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return "", errors.Wrap(err, "nat: failed to set ssdp read deadline")
+	}
+
+	buf := make([]byte, 2048)
+
+	for {
+		n, _, err := conn.ReadFrom(buf)
+
+		if err != nil {
+			return "", errors.New("nat: no ssdp response received from any gateway")
+		}
+
+		if loc := parseLocationHeader(buf[:n]); loc != "" {
+			return loc, nil
+		}
+	}
+}
+
+// parseLocationHeader extracts the LOCATION header's value out of a raw
+// SSDP response.
+func parseLocationHeader(resp []byte) string {
+	scanner := bufio.NewScanner(strings.NewReader(string(resp)))
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		idx := strings.IndexByte(line, ':')
+
+		if idx > 0 && strings.EqualFold(strings.TrimSpace(line[:idx]), "LOCATION") {
+			return strings.TrimSpace(line[idx+1:])
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,137 @@
+package nat
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// upnpSearchTarget is the SSDP search target for an Internet Gateway Device.
+const upnpSearchTarget = "urn:schemas-upnp-org:device:InternetGatewayDevice:1"
+
+// upnp discovers a gateway speaking UPnP Internet Gateway Device (IGD), and
+// maps ports through its WANIPConnection/WANPPPConnection service via SOAP.
+type upnp struct {
+	mu sync.Mutex
+
+	device     string
+	service    string
+	controlURL string
+}
+
+func (u *upnp) String() string {
+	u.mu.Lock()
+	device := u.device
+	u.mu.Unlock()
+
+	if device == "" {
+		return "UPnP"
+	}
+
+	return "UPnP(" + device + ")"
+}
+
+// discover locates the gateway's control URL via SSDP and its device
+// description, caching the result so later calls are free. It is safe to
+// call concurrently — e.g. from nat.Map's background renew goroutine
+// alongside a caller's own ExternalIP/AddMapping/DeleteMapping call.
+func (u *upnp) discover() (controlURL, service string, err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.controlURL != "" {
+		return u.controlURL, u.service, nil
+	}
+
+	loc, err := ssdpSearch(upnpSearchTarget, 2*time.Second)
+
+	if err != nil {
+		return "", "", errors.Wrap(err, "nat: no UPnP gateway found on the network")
+	}
+
+	controlURL, service, err = fetchControlURL(loc)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	u.device = loc
+	u.service = service
+	u.controlURL = controlURL
+
+	return controlURL, service, nil
+}
+
+func (u *upnp) ExternalIP() (net.IP, error) {
+	controlURL, service, err := u.discover()
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := soapCall(controlURL, service, "GetExternalIPAddress", "")
+
+	if err != nil {
+		return nil, err
+	}
+
+	ipStr := extractTag(resp, "NewExternalIPAddress")
+	ip := net.ParseIP(ipStr)
+
+	if ip == nil {
+		return nil, errors.Errorf("nat: gateway returned invalid external ip %q", ipStr)
+	}
+
+	return ip, nil
+}
+
+func (u *upnp) AddMapping(protocol string, external, internal int, lease time.Duration) (int, error) {
+	controlURL, service, err := u.discover()
+
+	if err != nil {
+		return 0, err
+	}
+
+	internalIP, err := preferredOutboundIP()
+
+	if err != nil {
+		return 0, errors.Wrap(err, "nat: failed to determine internal address for port mapping")
+	}
+
+	args := fmt.Sprintf(
+		"<NewRemoteHost></NewRemoteHost>"+
+			"<NewExternalPort>%d</NewExternalPort>"+
+			"<NewProtocol>%s</NewProtocol>"+
+			"<NewInternalPort>%d</NewInternalPort>"+
+			"<NewInternalClient>%s</NewInternalClient>"+
+			"<NewEnabled>1</NewEnabled>"+
+			"<NewPortMappingDescription>noise</NewPortMappingDescription>"+
+			"<NewLeaseDuration>%d</NewLeaseDuration>",
+		external, strings.ToUpper(protocol), internal, internalIP, int(lease.Seconds()))
+
+	if _, err := soapCall(controlURL, service, "AddPortMapping", args); err != nil {
+		return 0, err
+	}
+
+	return external, nil
+}
+
+func (u *upnp) DeleteMapping(protocol string, external, internal int) error {
+	controlURL, service, err := u.discover()
+
+	if err != nil {
+		return err
+	}
+
+	args := fmt.Sprintf(
+		"<NewRemoteHost></NewRemoteHost><NewExternalPort>%d</NewExternalPort><NewProtocol>%s</NewProtocol>",
+		external, strings.ToUpper(protocol))
+
+	_, err = soapCall(controlURL, service, "DeletePortMapping", args)
+
+	return err
+}
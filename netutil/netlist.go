@@ -0,0 +1,100 @@
+// Package netutil provides small helpers for restricting peer traffic to a
+// set of allowed IP ranges.
+package netutil
+
+import (
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Netlist is a list of IP networks used to restrict which addresses a
+// protocol is willing to talk to.
+type Netlist []*net.IPNet
+
+// ParseNetlist parses a comma-separated list of CIDR blocks, e.g.
+// "10.0.0.0/8,192.168.0.0/16", into a Netlist.
+func ParseNetlist(s string) (*Netlist, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var list Netlist
+
+	for _, cidr := range strings.Split(s, ",") {
+		cidr = strings.TrimSpace(cidr)
+
+		if cidr == "" {
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "netutil: invalid CIDR %q", cidr)
+		}
+
+		list = append(list, network)
+	}
+
+	return &list, nil
+}
+
+// Contains reports whether ip falls within any network in the list. A nil
+// or empty Netlist is treated as "allow everything".
+func (l *Netlist) Contains(ip net.IP) bool {
+	if l == nil || len(*l) == 0 {
+		return true
+	}
+
+	for _, network := range *l {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ContainsAddr resolves addr (a "host:port" string) and reports whether its
+// IP falls within the list.
+func (l *Netlist) ContainsAddr(addr string) bool {
+	if l == nil || len(*l) == 0 {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+
+	if err != nil {
+		host = addr
+	}
+
+	ip := net.ParseIP(host)
+
+	if ip == nil {
+		ips, err := net.LookupIP(host)
+
+		if err != nil || len(ips) == 0 {
+			return false
+		}
+
+		ip = ips[0]
+	}
+
+	return l.Contains(ip)
+}
+
+func (l *Netlist) String() string {
+	if l == nil || len(*l) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(*l))
+
+	for _, network := range *l {
+		parts = append(parts, network.String())
+	}
+
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,81 @@
+package netutil
+
+import "testing"
+
+func TestParseNetlist(t *testing.T) {
+	list, err := ParseNetlist("10.0.0.0/8, 192.168.0.0/16")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(*list) != 2 {
+		t.Fatalf("expected 2 networks, got %d", len(*list))
+	}
+}
+
+func TestParseNetlistEmpty(t *testing.T) {
+	list, err := ParseNetlist("")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if list != nil {
+		t.Fatalf("expected nil list for empty input, got %v", list)
+	}
+}
+
+func TestParseNetlistInvalidCIDR(t *testing.T) {
+	if _, err := ParseNetlist("not-a-cidr"); err == nil {
+		t.Fatal("expected an error for an invalid CIDR")
+	}
+}
+
+func TestNetlistContainsNilIsAllowAll(t *testing.T) {
+	var list *Netlist
+
+	if !list.ContainsAddr("203.0.113.5:80") {
+		t.Fatal("nil Netlist should allow every address")
+	}
+}
+
+func TestNetlistContainsAddr(t *testing.T) {
+	list, err := ParseNetlist("10.0.0.0/8")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !list.ContainsAddr("10.1.2.3:9000") {
+		t.Fatal("expected 10.1.2.3 to be allowed by 10.0.0.0/8")
+	}
+
+	if list.ContainsAddr("203.0.113.5:9000") {
+		t.Fatal("expected 203.0.113.5 to be denied by 10.0.0.0/8")
+	}
+}
+
+func TestNetlistContainsAddrWithoutPort(t *testing.T) {
+	list, err := ParseNetlist("10.0.0.0/8")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !list.ContainsAddr("10.1.2.3") {
+		t.Fatal("expected a bare IP (no port) to still be matched")
+	}
+}
+
+func TestNetlistString(t *testing.T) {
+	list, err := ParseNetlist("10.0.0.0/8")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, want := list.String(), "10.0.0.0/8"; got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+}
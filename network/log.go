@@ -0,0 +1,11 @@
+package network
+
+import "github.com/perlin-network/noise/logger"
+
+var defaultLogger logger.Logger = logger.Nop
+
+// SetLogger overrides the logger used by network's helpers (e.g.
+// BootstrapPeers), in place of the default no-op logger.
+func SetLogger(l logger.Logger) {
+	defaultLogger = l
+}
@@ -4,7 +4,6 @@ import (
 	"github.com/perlin-network/noise/peer"
 	"github.com/perlin-network/noise/protobuf"
 	"sync"
-	"github.com/perlin-network/noise/log"
 	"github.com/perlin-network/perlin-go/network/dht"
 )
 
@@ -40,12 +39,12 @@ func BootstrapPeers(network *Network, target peer.ID, count int) (addresses []st
 				response, err := network.Request(client, request)
 
 				if err != nil {
-					log.Debug(response)
+					defaultLogger.Debug("lookup node request failed", "id", peerId.Hex(), "addr", peerId.Address, "err", err)
 					return
 				}
 
 				if response, ok := response.(*protobuf.LookupNodeResponse); ok {
-					log.Debug(response)
+					defaultLogger.Debug("received lookup node response", "id", peerId.Hex(), "addr", peerId.Address)
 					responses <- response
 				}
 			}(popped)
@@ -68,6 +67,8 @@ func BootstrapPeers(network *Network, target peer.ID, count int) (addresses []st
 					queue = append(queue, peer)
 					visited[peer.Hex()] = struct{}{}
 
+					defaultLogger.Trace("expanding bootstrap peer", "id", peer.Hex(), "addr", peer.Address)
+
 					addresses = append(addresses, peer.Address)
 
 					publicKey := make([]byte, dht.IdSize)
@@ -0,0 +1,197 @@
+// Package nodedb persists observed peer identities to disk so a restarted
+// node can seed its routing table from previously known-good peers instead
+// of cold-starting from nothing but its configured bootstrap seeds.
+package nodedb
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+)
+
+var bucketName = []byte("nodes")
+
+// Record is what nodedb persists about one observed peer.
+type Record struct {
+	Checksum         [32]byte
+	Address          string
+	LastSeen         time.Time
+	LastPongReceived time.Time
+	FindFailCount    int
+}
+
+// DB is a bolt-backed store of Records, keyed by checksum.
+type DB struct {
+	bolt *bolt.DB
+}
+
+// Open opens (creating if necessary) the node database at path.
+func Open(path string) (*DB, error) {
+	bdb, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "nodedb: failed to open database")
+	}
+
+	err = bdb.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketName)
+		return err
+	})
+
+	if err != nil {
+		_ = bdb.Close()
+		return nil, errors.Wrap(err, "nodedb: failed to initialize schema")
+	}
+
+	return &DB{bolt: bdb}, nil
+}
+
+// Close closes the underlying database file.
+func (db *DB) Close() error {
+	return db.bolt.Close()
+}
+
+// Put upserts rec, keyed by its Checksum.
+func (db *DB) Put(rec *Record) error {
+	buf, err := json.Marshal(rec)
+
+	if err != nil {
+		return errors.Wrap(err, "nodedb: failed to marshal record")
+	}
+
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Put(rec.Checksum[:], buf)
+	})
+}
+
+// Get looks up the record for checksum, if any.
+func (db *DB) Get(checksum [32]byte) (*Record, bool, error) {
+	var rec *Record
+
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		buf := tx.Bucket(bucketName).Get(checksum[:])
+
+		if buf == nil {
+			return nil
+		}
+
+		rec = new(Record)
+		return json.Unmarshal(buf, rec)
+	})
+
+	if err != nil {
+		return nil, false, errors.Wrap(err, "nodedb: failed to read record")
+	}
+
+	return rec, rec != nil, nil
+}
+
+// Delete removes the record for checksum, if any.
+func (db *DB) Delete(checksum [32]byte) error {
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Delete(checksum[:])
+	})
+}
+
+// All returns every record in the database.
+func (db *DB) All() ([]*Record, error) {
+	var records []*Record
+
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).ForEach(func(k, v []byte) error {
+			rec := new(Record)
+
+			if err := json.Unmarshal(v, rec); err != nil {
+				return err
+			}
+
+			records = append(records, rec)
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "nodedb: failed to list records")
+	}
+
+	return records, nil
+}
+
+// Top returns up to k records with the most recent LastSeen, freshest
+// first.
+func (db *DB) Top(k int) ([]*Record, error) {
+	records, err := db.All()
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].LastSeen.After(records[j].LastSeen)
+	})
+
+	if len(records) > k {
+		records = records[:k]
+	}
+
+	return records, nil
+}
+
+// UpdateLastSeen bumps a record's LastSeen timestamp, creating the record
+// if it doesn't already exist.
+func (db *DB) UpdateLastSeen(checksum [32]byte, address string, t time.Time) error {
+	rec, _, err := db.Get(checksum)
+
+	if err != nil {
+		return err
+	}
+
+	if rec == nil {
+		rec = &Record{Checksum: checksum}
+	}
+
+	rec.Address = address
+	rec.LastSeen = t
+
+	return db.Put(rec)
+}
+
+// UpdateLastPong bumps a record's LastPongReceived timestamp and resets its
+// FindFailCount, creating the record if it doesn't already exist.
+func (db *DB) UpdateLastPong(checksum [32]byte, address string, t time.Time) error {
+	rec, _, err := db.Get(checksum)
+
+	if err != nil {
+		return err
+	}
+
+	if rec == nil {
+		rec = &Record{Checksum: checksum}
+	}
+
+	rec.Address = address
+	rec.LastPongReceived = t
+	rec.FindFailCount = 0
+
+	return db.Put(rec)
+}
+
+// IncrementFindFail records a failed liveness check against checksum.
+func (db *DB) IncrementFindFail(checksum [32]byte) error {
+	rec, ok, err := db.Get(checksum)
+
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return nil
+	}
+
+	rec.FindFailCount++
+
+	return db.Put(rec)
+}
@@ -0,0 +1,113 @@
+package skademlia
+
+import (
+	"time"
+
+	"github.com/perlin-network/noise"
+)
+
+const (
+	// DefaultMinDialBackoff is the backoff applied after the first failed
+	// dial to a peer.
+	DefaultMinDialBackoff = 30 * time.Second
+
+	// DefaultMaxDialBackoff is the cap backoff doubles towards on repeated
+	// consecutive dial failures.
+	DefaultMaxDialBackoff = 10 * time.Minute
+
+	// maxConsecutiveDialFailures is how many times in a row a dial must
+	// fail before the peer is evicted from the routing table, rather than
+	// just being left alone until its backoff elapses.
+	maxConsecutiveDialFailures = 3
+)
+
+// dialRecord tracks the outcome of past dial attempts to a single peer so
+// that PeerByID can back off instead of hammering an unreachable address.
+type dialRecord struct {
+	lastAttempt time.Time
+	backoff     time.Duration
+	failures    int
+}
+
+// inFlightDial lets concurrent PeerByID calls for the same ID coalesce onto
+// a single outgoing dial.
+type inFlightDial struct {
+	done chan struct{}
+	peer *noise.Peer
+	err  error
+}
+
+// dialRecordFor returns (creating if necessary) the dial history entry for
+// id.
+func (b *Protocol) dialRecordFor(id *ID) *dialRecord {
+	b.dialHistoryLock.Lock()
+	defer b.dialHistoryLock.Unlock()
+
+	record, ok := b.dialHistory[id.checksum]
+
+	if !ok {
+		record = &dialRecord{backoff: b.dialMinBackoff}
+		b.dialHistory[id.checksum] = record
+	}
+
+	return record
+}
+
+// shouldSkipDial reports whether id's backoff has not yet elapsed since its
+// last dial attempt.
+func (b *Protocol) shouldSkipDial(record *dialRecord) bool {
+	b.dialHistoryLock.Lock()
+	defer b.dialHistoryLock.Unlock()
+
+	return !record.lastAttempt.IsZero() && time.Since(record.lastAttempt) < record.backoff
+}
+
+// recordDialStart marks that a dial attempt to id is starting now.
+func (b *Protocol) recordDialStart(record *dialRecord) {
+	b.dialHistoryLock.Lock()
+	defer b.dialHistoryLock.Unlock()
+
+	record.lastAttempt = time.Now()
+}
+
+// recordDialSuccess resets id's backoff and failure count after a
+// successful dial.
+func (b *Protocol) recordDialSuccess(record *dialRecord) {
+	b.dialHistoryLock.Lock()
+	defer b.dialHistoryLock.Unlock()
+
+	record.backoff = b.dialMinBackoff
+	record.failures = 0
+}
+
+// recordDialFailure counts a failed dial, doubles id's backoff from the
+// second consecutive failure onward (capped at dialMaxBackoff), and
+// reports whether the peer has now failed enough consecutive times to
+// warrant eviction.
+func (b *Protocol) recordDialFailure(record *dialRecord) (evict bool) {
+	b.dialHistoryLock.Lock()
+	defer b.dialHistoryLock.Unlock()
+
+	record.failures++
+
+	// The record already starts at dialMinBackoff, so only double from the
+	// second consecutive failure onward — otherwise the first retry would
+	// wait 2x dialMinBackoff instead of dialMinBackoff.
+	if record.failures > 1 {
+		record.backoff *= 2
+		if record.backoff > b.dialMaxBackoff {
+			record.backoff = b.dialMaxBackoff
+		}
+	}
+
+	return record.failures >= maxConsecutiveDialFailures
+}
+
+// removeFromHistory clears id's dial history, so the next PeerByID call
+// dials immediately regardless of any standing backoff. Call this from
+// APIs that force a reconnect, e.g. removing a static peer override.
+func (b *Protocol) removeFromHistory(id *ID) {
+	b.dialHistoryLock.Lock()
+	delete(b.dialHistory, id.checksum)
+	b.dialHistoryLock.Unlock()
+}
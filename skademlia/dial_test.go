@@ -0,0 +1,125 @@
+package skademlia
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// newTestDialProtocol returns a Protocol with just enough state populated to
+// exercise the dial-backoff state machine, without the table/keys a real
+// Protocol needs (which aren't required by any of the dial.go helpers).
+func newTestDialProtocol() *Protocol {
+	return &Protocol{
+		dialMinBackoff: DefaultMinDialBackoff,
+		dialMaxBackoff: DefaultMaxDialBackoff,
+		dialHistory:    make(map[[blake2b.Size256]byte]*dialRecord),
+	}
+}
+
+func TestRecordDialFailureDoesNotDoubleOnFirstFailure(t *testing.T) {
+	b := newTestDialProtocol()
+	id := &ID{address: "127.0.0.1:3000"}
+
+	record := b.dialRecordFor(id)
+	b.recordDialFailure(record)
+
+	if record.backoff != DefaultMinDialBackoff {
+		t.Fatalf("backoff after first failure = %v, want %v", record.backoff, DefaultMinDialBackoff)
+	}
+}
+
+func TestRecordDialFailureDoublesFromSecondFailure(t *testing.T) {
+	b := newTestDialProtocol()
+	id := &ID{address: "127.0.0.1:3000"}
+
+	record := b.dialRecordFor(id)
+	b.recordDialFailure(record)
+	b.recordDialFailure(record)
+
+	if want := DefaultMinDialBackoff * 2; record.backoff != want {
+		t.Fatalf("backoff after second failure = %v, want %v", record.backoff, want)
+	}
+}
+
+func TestRecordDialFailureCapsAtMaxBackoff(t *testing.T) {
+	b := newTestDialProtocol()
+	id := &ID{address: "127.0.0.1:3000"}
+
+	record := b.dialRecordFor(id)
+
+	for i := 0; i < 20; i++ {
+		b.recordDialFailure(record)
+	}
+
+	if record.backoff != DefaultMaxDialBackoff {
+		t.Fatalf("backoff after repeated failures = %v, want cap of %v", record.backoff, DefaultMaxDialBackoff)
+	}
+}
+
+func TestRecordDialFailureEvictsAfterMaxConsecutiveFailures(t *testing.T) {
+	b := newTestDialProtocol()
+	id := &ID{address: "127.0.0.1:3000"}
+
+	record := b.dialRecordFor(id)
+
+	var evict bool
+
+	for i := 0; i < maxConsecutiveDialFailures; i++ {
+		evict = b.recordDialFailure(record)
+	}
+
+	if !evict {
+		t.Fatalf("expected eviction after %d consecutive failures", maxConsecutiveDialFailures)
+	}
+}
+
+func TestRecordDialSuccessResetsBackoffAndFailures(t *testing.T) {
+	b := newTestDialProtocol()
+	id := &ID{address: "127.0.0.1:3000"}
+
+	record := b.dialRecordFor(id)
+	b.recordDialFailure(record)
+	b.recordDialFailure(record)
+
+	b.recordDialSuccess(record)
+
+	if record.backoff != DefaultMinDialBackoff {
+		t.Fatalf("backoff after success = %v, want %v", record.backoff, DefaultMinDialBackoff)
+	}
+
+	if record.failures != 0 {
+		t.Fatalf("failures after success = %d, want 0", record.failures)
+	}
+}
+
+func TestShouldSkipDial(t *testing.T) {
+	b := newTestDialProtocol()
+	id := &ID{address: "127.0.0.1:3000"}
+
+	record := b.dialRecordFor(id)
+
+	if b.shouldSkipDial(record) {
+		t.Fatal("a record that has never been dialed should not be skipped")
+	}
+
+	b.recordDialStart(record)
+	record.backoff = time.Hour
+
+	if !b.shouldSkipDial(record) {
+		t.Fatal("a record dialed moments ago with a long backoff should be skipped")
+	}
+}
+
+func TestRemoveFromHistory(t *testing.T) {
+	b := newTestDialProtocol()
+	id := &ID{address: "127.0.0.1:3000"}
+
+	b.dialRecordFor(id)
+	b.removeFromHistory(id)
+
+	if _, ok := b.dialHistory[id.checksum]; ok {
+		t.Fatal("expected dial history entry to be removed")
+	}
+}
@@ -0,0 +1,24 @@
+package skademlia
+
+import (
+	"github.com/perlin-network/noise/nat"
+	"github.com/pkg/errors"
+)
+
+// NewWithNAT is like New, but resolves externalAddress automatically via m
+// instead of requiring operators to hand-configure one. It discovers the
+// public IP, requests a port mapping for port with a lease that it keeps
+// renewed in the background, and uses the resulting "host:port" as the
+// address the puzzle-based ID is computed over.
+//
+// Callers should close stop (e.g. on noise.Node shutdown) to tear down the
+// port mapping.
+func NewWithNAT(keys *Keypair, m nat.Interface, port int, stop <-chan struct{}) (*Protocol, error) {
+	address, err := nat.Map(m, "tcp", port, stop)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "skademlia: nat traversal failed")
+	}
+
+	return New(keys, address), nil
+}
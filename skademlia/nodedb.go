@@ -0,0 +1,184 @@
+package skademlia
+
+import (
+	"crypto/rand"
+	"time"
+
+	"github.com/perlin-network/noise"
+	"github.com/perlin-network/noise/nodedb"
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	// DefaultRefreshInterval is how often RunNodeDBRefresh re-walks the
+	// routing table, per standard Kademlia bucket refresh.
+	DefaultRefreshInterval = 1 * time.Hour
+
+	// DefaultNodeTTL is how stale a peer's last pong may be before it is
+	// evicted during a refresh pass.
+	DefaultNodeTTL = 24 * time.Hour
+
+	// nodeDBSeedCount is how many of the freshest entries in the node
+	// database are used to seed Bootstrap, alongside the configured
+	// bootstrap seeds already in the routing table.
+	nodeDBSeedCount = 16
+)
+
+// db lazily opens the node database the first time it's needed, returning
+// (nil, nil) if no database path was configured.
+func (b *Protocol) db() (*nodedb.DB, error) {
+	if b.nodeDBPath == "" {
+		return nil, nil
+	}
+
+	b.nodeDBOnce.Do(func() {
+		b.nodeDBHandle, b.nodeDBErr = nodedb.Open(b.nodeDBPath)
+	})
+
+	return b.nodeDBHandle, b.nodeDBErr
+}
+
+// seedFromNodeDB admits the freshest known peers from the node database
+// into the routing table, so Bootstrap's α-parallel lookup starts from a
+// far richer set of candidates than just the configured bootstrap seeds.
+func (b *Protocol) seedFromNodeDB() {
+	db, err := b.db()
+
+	if err != nil || db == nil {
+		return
+	}
+
+	records, err := db.Top(nodeDBSeedCount)
+
+	if err != nil {
+		return
+	}
+
+	for _, rec := range records {
+		id := &ID{address: rec.Address}
+		copy(id.checksum[:], rec.Checksum[:])
+
+		_ = b.Update(id) // best-effort: just a seed hint, not a verified peer; Update enforces netRestrict
+	}
+}
+
+// bumpLastSeen records that traffic was just received from id, the
+// equivalent of the removed bumpActive.
+func (b *Protocol) bumpLastSeen(id *ID) {
+	db, err := b.db()
+
+	if err != nil || db == nil {
+		return
+	}
+
+	_ = db.UpdateLastSeen(id.checksum, id.address, time.Now())
+}
+
+// bumpLastPong records that id answered a PING, resetting its find-failure
+// count.
+func (b *Protocol) bumpLastPong(id *ID) {
+	db, err := b.db()
+
+	if err != nil || db == nil {
+		return
+	}
+
+	_ = db.UpdateLastPong(id.checksum, id.address, time.Now())
+}
+
+// RunNodeDBRefresh periodically walks every bucket that hasn't been looked
+// up recently, issuing a FindNode on a random ID that falls into it, and
+// evicts peers whose last pong is older than nodeTTL. It runs until stop is
+// closed.
+func (b *Protocol) RunNodeDBRefresh(node *noise.Node, stop <-chan struct{}) {
+	ticker := time.NewTicker(DefaultRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			b.refreshBuckets(node)
+			b.expireStaleNodes()
+		}
+	}
+}
+
+// refreshBuckets issues a FindNode for a random ID in each bucket that
+// hasn't already been refreshed within the last DefaultRefreshInterval,
+// which in turn causes Kademlia lookups to pick up peers that would
+// otherwise go stale from disuse.
+func (b *Protocol) refreshBuckets(node *noise.Node) {
+	now := time.Now()
+
+	for i := range b.table.buckets {
+		b.bucketRefreshLock.Lock()
+		last, seen := b.bucketRefreshedAt[i]
+		b.bucketRefreshLock.Unlock()
+
+		if seen && now.Sub(last) < DefaultRefreshInterval {
+			continue
+		}
+
+		target := randomIDForBucket(b.table.self, i)
+		b.FindNode(node, target, b.table.bucketSize, 3, 1)
+
+		b.bucketRefreshLock.Lock()
+		b.bucketRefreshedAt[i] = now
+		b.bucketRefreshLock.Unlock()
+	}
+}
+
+// expireStaleNodes evicts any peer, in the table or the node database,
+// whose last pong is older than nodeTTL.
+func (b *Protocol) expireStaleNodes() {
+	db, err := b.db()
+
+	if err != nil || db == nil {
+		return
+	}
+
+	records, err := db.All()
+
+	if err != nil {
+		return
+	}
+
+	cutoff := time.Now().Add(-b.nodeTTL)
+
+	for _, rec := range records {
+		if rec.LastPongReceived.After(cutoff) {
+			continue
+		}
+
+		id := &ID{address: rec.Address}
+		copy(id.checksum[:], rec.Checksum[:])
+
+		bucket := b.table.buckets[getBucketID(b.table.self.checksum, id.checksum)]
+		b.table.Delete(bucket, id)
+
+		_ = db.Delete(rec.Checksum)
+	}
+}
+
+// randomIDForBucket returns an ID whose checksum shares self's prefix up to
+// bucketIdx bits and differs at bit bucketIdx — i.e. an ID that falls into
+// the bucket at index bucketIdx. The bytes after the one holding that bit
+// are randomized; the remaining bits of that byte keep self's values.
+func randomIDForBucket(self *ID, bucketIdx int) *ID {
+	var checksum [blake2b.Size256]byte
+	copy(checksum[:], self.checksum[:])
+
+	byteIdx, bitIdx := bucketIdx/8, bucketIdx%8
+
+	if byteIdx < len(checksum) {
+		checksum[byteIdx] ^= 1 << uint(7-bitIdx)
+
+		if _, err := rand.Read(checksum[byteIdx+1:]); err != nil {
+			return self
+		}
+	}
+
+	return &ID{checksum: checksum}
+}
@@ -2,8 +2,10 @@ package skademlia
 
 import (
 	"bytes"
-	"fmt"
 	"github.com/perlin-network/noise"
+	"github.com/perlin-network/noise/logger"
+	"github.com/perlin-network/noise/netutil"
+	"github.com/perlin-network/noise/nodedb"
 	"github.com/phf/go-queue/queue"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/blake2b"
@@ -37,6 +39,37 @@ type Protocol struct {
 
 	peers     map[[blake2b.Size256]byte]*noise.Peer
 	peersLock sync.Mutex
+
+	bootstrapOnly bool
+
+	netRestrict *netutil.Netlist
+
+	dialMinBackoff time.Duration
+	dialMaxBackoff time.Duration
+
+	dialHistory     map[[blake2b.Size256]byte]*dialRecord
+	dialHistoryLock sync.Mutex
+
+	inFlightDials     map[[blake2b.Size256]byte]*inFlightDial
+	inFlightDialsLock sync.Mutex
+
+	sendQueueSize int
+	sendTimeout   time.Duration
+
+	peerQueues     map[*noise.Peer]*peerQueues
+	peerQueuesLock sync.Mutex
+
+	nodeDBPath string
+	nodeTTL    time.Duration
+
+	nodeDBOnce   sync.Once
+	nodeDBHandle *nodedb.DB
+	nodeDBErr    error
+
+	bucketRefreshedAt map[int]time.Time
+	bucketRefreshLock sync.Mutex
+
+	log logger.Logger
 }
 
 func New(keys *Keypair, externalAddress string) *Protocol {
@@ -54,6 +87,23 @@ func New(keys *Keypair, externalAddress string) *Protocol {
 		findNodeTimeout:  3 * time.Second,
 
 		peers: make(map[[blake2b.Size256]byte]*noise.Peer),
+
+		dialMinBackoff: DefaultMinDialBackoff,
+		dialMaxBackoff: DefaultMaxDialBackoff,
+
+		dialHistory:   make(map[[blake2b.Size256]byte]*dialRecord),
+		inFlightDials: make(map[[blake2b.Size256]byte]*inFlightDial),
+
+		sendQueueSize: DefaultSendQueueSize,
+		sendTimeout:   DefaultSendTimeout,
+
+		peerQueues: make(map[*noise.Peer]*peerQueues),
+
+		nodeTTL: DefaultNodeTTL,
+
+		bucketRefreshedAt: make(map[int]time.Time),
+
+		log: logger.Nop,
 	}
 }
 
@@ -82,7 +132,132 @@ func (b *Protocol) WithHandshakeTimeout(handshakeTimeout time.Duration) *Protoco
 	return b
 }
 
+// WithBootstrapOnly marks this protocol instance as discovery-only: it will
+// still answer PING and LOOKUP requests and maintain its routing table, but
+// will never be used to originate application traffic or be registered
+// against higher-level protocols. Bootnodes should set this to true.
+func (b *Protocol) WithBootstrapOnly(bootstrapOnly bool) *Protocol {
+	b.bootstrapOnly = bootstrapOnly
+	return b
+}
+
+// BootstrapOnly reports whether this protocol instance is running in
+// discovery-only mode.
+func (b *Protocol) BootstrapOnly() bool {
+	return b.bootstrapOnly
+}
+
+// Self returns this node's own ID as recorded in its routing table.
+func (b *Protocol) Self() *ID {
+	return b.table.self
+}
+
+// WithDialBackoff sets the initial and maximum backoff applied between
+// redial attempts to a peer that has failed to connect. The first failure
+// waits min; backoff doubles on each further consecutive failure, capped at
+// max, and resets to min the next time
+// the peer is successfully dialed.
+func (b *Protocol) WithDialBackoff(min, max time.Duration) *Protocol {
+	b.dialMinBackoff = min
+	b.dialMaxBackoff = max
+	return b
+}
+
+// WithSendQueueSize sets the per-peer, per-opcode-class byte budget for
+// queued outgoing messages. Once a queue exceeds bytes, the oldest queued
+// messages are dropped to make room for new ones rather than blocking the
+// caller (e.g. a FindNode α-parallel worker) indefinitely on one stuck peer.
+func (b *Protocol) WithSendQueueSize(bytes int) *Protocol {
+	b.sendQueueSize = bytes
+	return b
+}
+
+// WithSendTimeout sets how long a single queued send may take before the
+// peer is considered unresponsive.
+func (b *Protocol) WithSendTimeout(d time.Duration) *Protocol {
+	b.sendTimeout = d
+	return b
+}
+
+// WithNodeDB persists observed peer identities to a bolt-backed database at
+// path, so that Bootstrap can seed its lookup from previously known-good
+// peers instead of only the configured bootstrap seeds.
+func (b *Protocol) WithNodeDB(path string) *Protocol {
+	b.nodeDBPath = path
+	return b
+}
+
+// WithNodeTTL sets how stale a peer's last pong may be before it is evicted
+// by the periodic bucket refresh.
+func (b *Protocol) WithNodeTTL(ttl time.Duration) *Protocol {
+	b.nodeTTL = ttl
+	return b
+}
+
+// WithLogger injects l as the destination for this protocol instance's
+// structured log output, in place of the default no-op logger.
+func (b *Protocol) WithLogger(l logger.Logger) *Protocol {
+	b.log = l
+	return b
+}
+
+// GetPeerQueues reports the current occupancy of every connected peer's
+// send queues, for observability.
+func (b *Protocol) GetPeerQueues() []PeerQueueStat {
+	b.peerQueuesLock.Lock()
+	defer b.peerQueuesLock.Unlock()
+
+	var stats []PeerQueueStat
+
+	for _, pq := range b.peerQueues {
+		for class, q := range pq.queues {
+			bytes, packets, highWater := q.stat()
+
+			stats = append(stats, PeerQueueStat{
+				ID:        pq.id,
+				Opcode:    QueueClass(class),
+				Bytes:     bytes,
+				Packets:   packets,
+				HighWater: highWater,
+			})
+		}
+	}
+
+	return stats
+}
+
+// WithNetRestrict restricts which peers this protocol instance will
+// handshake with, ping, dial, or admit into its routing table to those
+// whose address resolves into one of the CIDR ranges in list. Passing a nil
+// list lifts the restriction. This lets operators run private overlays or
+// blocklist known-bad ranges without patching the protocol.
+func (b *Protocol) WithNetRestrict(list *netutil.Netlist) *Protocol {
+	b.netRestrict = list
+
+	if list != nil {
+		for _, bucket := range b.table.buckets {
+			bucket.Lock()
+			for e := bucket.Front(); e != nil; {
+				next := e.Next()
+
+				if id := e.Value.(*ID); !b.netRestrict.ContainsAddr(id.address) {
+					bucket.Remove(e)
+				}
+
+				e = next
+			}
+			bucket.Unlock()
+		}
+	}
+
+	return b
+}
+
 func (b *Protocol) Peers(node *noise.Node) (peers []*noise.Peer) {
+	if b.bootstrapOnly {
+		return nil
+	}
+
 	ids := b.table.FindClosest(b.table.self, b.table.bucketSize)
 
 	for _, id := range ids {
@@ -95,6 +270,10 @@ func (b *Protocol) Peers(node *noise.Node) (peers []*noise.Peer) {
 }
 
 func (b *Protocol) PeerByID(node *noise.Node, id *ID) *noise.Peer {
+	if !b.netRestrict.ContainsAddr(id.address) {
+		return nil
+	}
+
 	b.peersLock.Lock()
 	peer, recorded := b.peers[id.checksum]
 	b.peersLock.Unlock()
@@ -109,14 +288,44 @@ func (b *Protocol) PeerByID(node *noise.Node, id *ID) *noise.Peer {
 		return peer
 	}
 
-	peer, err := node.Dial(id.address)
+	record := b.dialRecordFor(id)
 
-	if err != nil {
-		b.evict(id)
+	b.inFlightDialsLock.Lock()
+	if dial, ok := b.inFlightDials[id.checksum]; ok {
+		b.inFlightDialsLock.Unlock()
+		<-dial.done
+		return dial.peer
+	}
+
+	if b.shouldSkipDial(record) {
+		b.inFlightDialsLock.Unlock()
+		b.log.Debug("skipping dial, backoff not yet elapsed", "id", id, "addr", id.address)
 		return nil
 	}
 
-	return peer
+	dial := &inFlightDial{done: make(chan struct{})}
+	b.inFlightDials[id.checksum] = dial
+	b.inFlightDialsLock.Unlock()
+
+	b.recordDialStart(record)
+	dial.peer, dial.err = node.Dial(id.address)
+
+	b.inFlightDialsLock.Lock()
+	delete(b.inFlightDials, id.checksum)
+	b.inFlightDialsLock.Unlock()
+	close(dial.done)
+
+	if dial.err != nil {
+		if b.recordDialFailure(record) {
+			b.evict(id)
+		}
+
+		return nil
+	}
+
+	b.recordDialSuccess(record)
+
+	return dial.peer
 }
 
 func wrap(f func() error) {
@@ -127,27 +336,36 @@ func (b *Protocol) Ping(ctx noise.Context) (*ID, error) {
 	mux := ctx.Peer().Mux()
 	defer wrap(mux.Close)
 
-	if err := mux.Send(0x03, nil); err != nil {
+	if err := b.queuedSend(ctx.Peer(), 0x03, nil, mux.Send); err != nil {
 		return nil, errors.Wrap(err, "failed to send ping")
 	}
 
 	var buf []byte
 
+	start := time.Now()
+
 	select {
 	case <-ctx.Done():
 		return nil, noise.ErrDisconnect
 	case <-time.After(b.handshakeTimeout):
+		b.log.Warn("timed out waiting for pong", "addr", ctx.Peer().Addr())
 		return nil, errors.Wrap(noise.ErrTimeout, "timed out receiving pong")
 	case ctx := <-mux.Recv(0x03):
 		buf = ctx.Bytes()
 	}
 
+	rtt := time.Since(start)
+
 	id, err := UnmarshalID(bytes.NewReader(buf))
 
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to unmarshal pong")
 	}
 
+	if !b.netRestrict.ContainsAddr(id.address) {
+		return nil, errors.Errorf("peer address %s is outside of the allowed network range", id.address)
+	}
+
 	if err := verifyPuzzle(id.checksum, id.nonce, b.c1, b.c2); err != nil {
 		return nil, errors.Wrap(err, "peer connected with invalid id")
 	}
@@ -156,6 +374,8 @@ func (b *Protocol) Ping(ctx noise.Context) (*ID, error) {
 		return nil, errors.New("peer id is too similar to ours")
 	}
 
+	b.log.Trace("received pong", "id", &id, "addr", id.address, "rtt", rtt)
+
 	return &id, err
 }
 
@@ -163,7 +383,7 @@ func (b *Protocol) Lookup(ctx noise.Context, target *ID) (IDs, error) {
 	mux := ctx.Peer().Mux()
 	defer wrap(mux.Close)
 
-	if err := mux.Send(0x04, target.Marshal()); err != nil {
+	if err := b.queuedSend(ctx.Peer(), 0x04, target.Marshal(), mux.Send); err != nil {
 		return nil, errors.Wrap(err, "failed to send find node request")
 	}
 
@@ -173,6 +393,7 @@ func (b *Protocol) Lookup(ctx noise.Context, target *ID) (IDs, error) {
 	case <-ctx.Done():
 		return nil, noise.ErrDisconnect
 	case <-time.After(b.handshakeTimeout):
+		b.log.Warn("timed out waiting for lookup response", "addr", ctx.Peer().Addr(), "target", target)
 		return nil, errors.Wrap(noise.ErrTimeout, "timed out receiving finde node response")
 	case ctx := <-mux.Recv(0x04):
 		buf = ctx.Bytes()
@@ -182,6 +403,10 @@ func (b *Protocol) Lookup(ctx noise.Context, target *ID) (IDs, error) {
 }
 
 func (b *Protocol) Handshake(ctx noise.Context) (*ID, error) {
+	if !b.netRestrict.ContainsAddr(ctx.Peer().Addr().String()) {
+		return nil, errors.Errorf("peer address %s is outside of the allowed network range", ctx.Peer().Addr())
+	}
+
 	signal := ctx.Peer().RegisterSignal(SignalHandshakeComplete)
 	defer signal()
 
@@ -231,6 +456,8 @@ func (b *Protocol) Handshake(ctx noise.Context) (*ID, error) {
 		return nil, err
 	}
 
+	b.bumpLastPong(id)
+
 	b.peersLock.Lock()
 	_, existed := b.peers[id.checksum]
 	b.peersLock.Unlock()
@@ -253,8 +480,11 @@ func (b *Protocol) Handshake(ctx noise.Context) (*ID, error) {
 		return nil, err
 	}
 
+	b.setPeerQueuesID(ctx.Peer(), id)
+
 	ctx.Peer().InterceptErrors(func(err error) {
 		delete(b.peers, id.checksum)
+		b.removePeerQueues(ctx.Peer())
 
 		if err, ok := err.(net.Error); ok && err.Timeout() {
 			b.evict(id)
@@ -268,17 +498,23 @@ func (b *Protocol) Handshake(ctx noise.Context) (*ID, error) {
 	})
 
 	ctx.Peer().AfterRecv(func() {
+		b.bumpLastSeen(id)
+
 		if err := b.Update(id); err != nil {
 			ctx.Peer().Disconnect(err)
 		}
 	})
 
-	fmt.Printf("Registered to S/Kademlia: %s\n", id)
+	b.log.Info("registered peer to s/kademlia", "id", id, "addr", id.address, "checksum", id.checksum)
 
 	return id, nil
 }
 
 func (b *Protocol) Update(id *ID) error {
+	if !b.netRestrict.ContainsAddr(id.address) {
+		return errors.Errorf("peer address %s is outside of the allowed network range", id.address)
+	}
+
 	for b.table.Update(id) == ErrBucketFull {
 		bucket := b.table.buckets[getBucketID(b.table.self.checksum, id.checksum)]
 
@@ -309,7 +545,7 @@ func (b *Protocol) Update(id *ID) error {
 			continue
 		}
 
-		fmt.Printf("Routing table is full; evicting peer %s.\n", id)
+		b.log.Warn("routing table full, rejecting new peer", "id", id, "bucket", getBucketID(b.table.self.checksum, id.checksum))
 
 		return errors.Wrap(noise.ErrDisconnect, "must reject peer: cannot evict any peers to make room for new peer")
 	}
@@ -318,6 +554,8 @@ func (b *Protocol) Update(id *ID) error {
 }
 
 func (b *Protocol) Bootstrap(node *noise.Node) (results []*ID) {
+	b.seedFromNodeDB()
+
 	return b.FindNode(node, b.table.self, b.table.bucketSize, 3, 8)
 }
 
@@ -411,8 +649,12 @@ func (b *Protocol) FindNode(node *noise.Node, target *ID, k int, a int, d int) (
 }
 
 func (b *Protocol) evict(id *ID) {
-	fmt.Printf("Peer %s could not be reached, and has been evicted.\n", id)
+	b.log.Warn("peer could not be reached, evicting", "id", id, "addr", id.address, "checksum", id.checksum)
 
 	bucket := b.table.buckets[getBucketID(b.table.self.checksum, id.checksum)]
 	b.table.Delete(bucket, id)
-}
\ No newline at end of file
+
+	// Clear id's dial history too, so it isn't held in memory indefinitely
+	// and a later re-admission (e.g. via seedFromNodeDB) can dial it fresh.
+	b.removeFromHistory(id)
+}
@@ -0,0 +1,244 @@
+package skademlia
+
+import (
+	"sync"
+	"time"
+
+	"github.com/perlin-network/noise"
+	"github.com/pkg/errors"
+)
+
+// QueueClass partitions a peer's outgoing S/Kademlia traffic so that queued
+// lookup traffic can't hold up queued control traffic sharing the same mux.
+type QueueClass int
+
+const (
+	// QueueControl carries PING traffic.
+	QueueControl QueueClass = iota
+	// QueueLookup carries FIND_NODE traffic.
+	QueueLookup
+
+	numQueueClasses
+)
+
+const (
+	// DefaultSendQueueSize is the default per-peer, per-class byte budget.
+	DefaultSendQueueSize = 4 << 20 // 4 MiB
+
+	// DefaultSendTimeout is how long a queued send may take before the
+	// peer is considered stuck.
+	DefaultSendTimeout = 3 * time.Second
+)
+
+// PeerQueueStat reports the occupancy of one (peer, opcode class) send
+// queue, for observability.
+type PeerQueueStat struct {
+	ID        *ID
+	Opcode    QueueClass
+	Bytes     int
+	Packets   int
+	HighWater int
+}
+
+type queuedSend struct {
+	opcode  byte
+	payload []byte
+	send    func(opcode byte, payload []byte) error
+	errc    chan error
+}
+
+// sendQueue is a bounded, byte-budgeted FIFO. Pushing past the budget drops
+// the oldest queued messages to make room, rather than blocking the caller.
+type sendQueue struct {
+	mu        sync.Mutex
+	items     []queuedSend
+	bytes     int
+	packets   int
+	highWater int
+	maxBytes  int
+	wake      chan struct{}
+}
+
+func newSendQueue(maxBytes int) *sendQueue {
+	return &sendQueue{maxBytes: maxBytes, wake: make(chan struct{}, 1)}
+}
+
+func (q *sendQueue) push(item queuedSend) {
+	q.mu.Lock()
+
+	for q.bytes+len(item.payload) > q.maxBytes && len(q.items) > 0 {
+		dropped := q.items[0]
+		q.items = q.items[1:]
+		q.bytes -= len(dropped.payload)
+		q.packets--
+
+		if dropped.errc != nil {
+			dropped.errc <- errors.New("skademlia: queued message dropped, send queue byte budget exceeded")
+		}
+	}
+
+	q.items = append(q.items, item)
+	q.bytes += len(item.payload)
+	q.packets++
+
+	if q.bytes > q.highWater {
+		q.highWater = q.bytes
+	}
+
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// pop returns the next queued message, if any, without blocking.
+func (q *sendQueue) pop() (queuedSend, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) == 0 {
+		return queuedSend{}, false
+	}
+
+	item := q.items[0]
+	q.items = q.items[1:]
+	q.bytes -= len(item.payload)
+	q.packets--
+
+	return item, true
+}
+
+func (q *sendQueue) stat() (bytes, packets, highWater int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.bytes, q.packets, q.highWater
+}
+
+// peerQueues holds the per-opcode-class send queues for one connected peer.
+// Queues are drained in class order, so control traffic (PING) always jumps
+// ahead of bulk lookup traffic.
+type peerQueues struct {
+	id     *ID
+	queues [numQueueClasses]*sendQueue
+}
+
+func newPeerQueues(id *ID, maxBytes int) *peerQueues {
+	pq := &peerQueues{id: id}
+
+	for i := range pq.queues {
+		pq.queues[i] = newSendQueue(maxBytes)
+	}
+
+	return pq
+}
+
+func (pq *peerQueues) popAny() (queuedSend, bool) {
+	for _, q := range pq.queues {
+		if item, ok := q.pop(); ok {
+			return item, true
+		}
+	}
+
+	return queuedSend{}, false
+}
+
+func classOf(opcode byte) QueueClass {
+	if opcode == 0x04 {
+		return QueueLookup
+	}
+
+	return QueueControl
+}
+
+// queuedSend routes a send through peer's bounded, prioritized send queues
+// instead of writing to the wire directly, so a slow peer only ever holds up
+// its own queued messages. A dispatcher goroutine (started the first time a
+// peer is seen) drains the queues in class order and invokes send to
+// actually perform it. It performs one send at a time, so a send in progress
+// delays every message queued behind it, whatever its class.
+//
+// send must be bound to the same mux/session the caller is listening on
+// for the response (e.g. a Mux's Send method) — the dispatcher only
+// sequences the call, it never substitutes a mux of its own, since each
+// call to Peer().Mux() opens a distinct, call-scoped session.
+func (b *Protocol) queuedSend(peer *noise.Peer, opcode byte, payload []byte, send func(opcode byte, payload []byte) error) error {
+	pq, dispatch := b.getOrCreatePeerQueues(peer)
+
+	if dispatch {
+		go b.runDispatcher(peer, pq)
+	}
+
+	errc := make(chan error, 1)
+	pq.queues[classOf(opcode)].push(queuedSend{opcode: opcode, payload: payload, send: send, errc: errc})
+
+	select {
+	case err := <-errc:
+		return err
+	case <-time.After(b.sendTimeout):
+		return errors.Wrap(noise.ErrTimeout, "send queue: timed out waiting for queued message to be sent")
+	}
+}
+
+// getOrCreatePeerQueues returns the queues for peer, creating them (and
+// reporting dispatch = true) the first time peer is seen.
+func (b *Protocol) getOrCreatePeerQueues(peer *noise.Peer) (pq *peerQueues, dispatch bool) {
+	b.peerQueuesLock.Lock()
+	defer b.peerQueuesLock.Unlock()
+
+	pq, ok := b.peerQueues[peer]
+
+	if !ok {
+		pq = newPeerQueues(nil, b.sendQueueSize)
+		b.peerQueues[peer] = pq
+		dispatch = true
+	}
+
+	return pq, dispatch
+}
+
+// removePeerQueues tears down the queues associated with peer, called once
+// the peer disconnects.
+func (b *Protocol) removePeerQueues(peer *noise.Peer) {
+	b.peerQueuesLock.Lock()
+	delete(b.peerQueues, peer)
+	b.peerQueuesLock.Unlock()
+}
+
+// setPeerQueuesID records id against peer's queues once its identity is
+// known, so GetPeerQueues can report it.
+func (b *Protocol) setPeerQueuesID(peer *noise.Peer, id *ID) {
+	b.peerQueuesLock.Lock()
+	if pq, ok := b.peerQueues[peer]; ok {
+		pq.id = id
+	}
+	b.peerQueuesLock.Unlock()
+}
+
+// runDispatcher drains peer's send queues in class order (control before
+// lookup) until peer disconnects, performing each queued send against the
+// mux/session its own caller supplied.
+func (b *Protocol) runDispatcher(peer *noise.Peer, pq *peerQueues) {
+	for {
+		item, ok := pq.popAny()
+
+		if !ok {
+			select {
+			case <-peer.Ctx().Done():
+				return
+			case <-pq.queues[QueueControl].wake:
+				continue
+			case <-pq.queues[QueueLookup].wake:
+				continue
+			}
+		}
+
+		err := item.send(item.opcode, item.payload)
+
+		if item.errc != nil {
+			item.errc <- err
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package skademlia
+
+import "testing"
+
+func TestClassOf(t *testing.T) {
+	if got := classOf(0x04); got != QueueLookup {
+		t.Fatalf("classOf(0x04) = %v, want QueueLookup", got)
+	}
+
+	if got := classOf(0x03); got != QueueControl {
+		t.Fatalf("classOf(0x03) = %v, want QueueControl", got)
+	}
+}
+
+func TestSendQueuePushPop(t *testing.T) {
+	q := newSendQueue(1024)
+
+	q.push(queuedSend{opcode: 0x03, payload: []byte("ping")})
+
+	item, ok := q.pop()
+
+	if !ok {
+		t.Fatal("expected an item to be popped")
+	}
+
+	if string(item.payload) != "ping" {
+		t.Fatalf("popped payload = %q, want %q", item.payload, "ping")
+	}
+
+	if _, ok := q.pop(); ok {
+		t.Fatal("expected the queue to be empty after popping its only item")
+	}
+}
+
+func TestSendQueueDropsOldestWhenBudgetExceeded(t *testing.T) {
+	q := newSendQueue(10)
+
+	oldest := make(chan error, 1)
+	q.push(queuedSend{payload: make([]byte, 6), errc: oldest})
+	q.push(queuedSend{payload: make([]byte, 6), errc: make(chan error, 1)})
+
+	select {
+	case err := <-oldest:
+		if err == nil {
+			t.Fatal("expected the dropped item's errc to receive an error")
+		}
+	default:
+		t.Fatal("expected the oldest item to be dropped and its errc signaled")
+	}
+
+	bytes, packets, _ := q.stat()
+
+	if packets != 1 {
+		t.Fatalf("packets after drop = %d, want 1", packets)
+	}
+
+	if bytes != 6 {
+		t.Fatalf("bytes after drop = %d, want 6", bytes)
+	}
+}
+
+func TestSendQueueHighWater(t *testing.T) {
+	q := newSendQueue(1024)
+
+	q.push(queuedSend{payload: make([]byte, 100)})
+	q.pop()
+	q.push(queuedSend{payload: make([]byte, 10)})
+
+	_, _, highWater := q.stat()
+
+	if highWater != 100 {
+		t.Fatalf("highWater = %d, want 100 (should track the peak, not the current size)", highWater)
+	}
+}
+
+func TestPeerQueuesPopAnyPrioritizesControl(t *testing.T) {
+	pq := newPeerQueues(nil, 1024)
+
+	pq.queues[QueueLookup].push(queuedSend{opcode: 0x04, payload: []byte("lookup")})
+	pq.queues[QueueControl].push(queuedSend{opcode: 0x03, payload: []byte("ping")})
+
+	item, ok := pq.popAny()
+
+	if !ok {
+		t.Fatal("expected an item to be popped")
+	}
+
+	if string(item.payload) != "ping" {
+		t.Fatalf("popAny returned %q first, want control traffic (%q) to jump the queue", item.payload, "ping")
+	}
+}